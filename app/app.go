@@ -4,14 +4,18 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/beck-8/subs-check/app/monitor"
+	"github.com/beck-8/subs-check/app/registry"
 	"github.com/beck-8/subs-check/assets"
 	"github.com/beck-8/subs-check/check"
 	"github.com/beck-8/subs-check/config"
@@ -25,15 +29,21 @@ import (
 
 // App 结构体用于管理应用程序状态
 type App struct {
-	configPath string
-	interval   int
-	watcher    *fsnotify.Watcher
-	checkChan  chan struct{} // 触发检测的通道
-	checking   atomic.Bool   // 检测状态标志
-	ticker     *time.Ticker
-	done       chan struct{} // 用于结束ticker goroutine的信号
-	cron       *cron.Cron    // crontab调度器
-	version    string
+	configPath     string
+	interval       int
+	watcher        *fsnotify.Watcher
+	checkChan      chan struct{} // 触发检测的通道
+	checking       atomic.Bool   // 检测状态标志
+	ticker         *time.Ticker
+	done           chan struct{} // 用于结束ticker goroutine的信号
+	cron           *cron.Cron    // crontab调度器
+	version        string
+	mux            *http.ServeMux // HTTP路由，供各API模块注册
+	httpServer     *http.Server
+	resultHub      *resultWatchHub    // 检测结果的快照与增量事件，供 /api/results(/watch) 使用
+	registry       *registry.Registry // 可选的服务注册客户端，未启用时为nil
+	gitSyncedFiles []string           // 本轮从 git+ 订阅源同步下来的本地节点文件，参与本轮检测
+	fileSubUrls    []string           // 配置文件中声明的订阅列表快照，用于与动态发现结果合并时保持不变的基线
 }
 
 // New 创建新的应用实例
@@ -46,6 +56,7 @@ func New(version string) *App {
 		checkChan:  make(chan struct{}),
 		done:       make(chan struct{}),
 		version:    version,
+		resultHub:  newResultWatchHub(),
 	}
 }
 
@@ -61,6 +72,10 @@ func (app *App) Initialize() error {
 		return fmt.Errorf("加载配置文件失败: %w", err)
 	}
 
+	// 记录文件中配置的订阅列表快照，作为与注册中心动态发现结果合并时不变的基线，
+	// 避免合并结果被反复写回 config.GlobalConfig.SubUrls 后污染这份基线
+	app.fileSubUrls = append([]string(nil), config.GlobalConfig.SubUrls...)
+
 	// 初始化配置文件监听
 	if err := app.initConfigWatcher(); err != nil {
 		return fmt.Errorf("初始化配置文件监听失败: %w", err)
@@ -92,6 +107,14 @@ func (app *App) Initialize() error {
 	// 启动内存监控
 	monitor.StartMemoryMonitor()
 
+	// 注入订阅URL退避期判断函数，使 proxies 在抓取前能跳过仍处于退避期的订阅
+	proxies.SetEligibilityChecker(app.isSubUrlEligible)
+
+	// 初始化服务注册与动态订阅发现（可选）
+	if err := app.initRegistry(); err != nil {
+		return fmt.Errorf("初始化服务注册失败: %w", err)
+	}
+
 	// 设置信号处理器
 	utils.SetupSignalHandler(&check.ForceClose)
 	return nil
@@ -107,6 +130,11 @@ func (app *App) Run() {
 		if app.cron != nil {
 			app.cron.Stop()
 		}
+		if app.registry != nil {
+			if err := app.registry.Deregister(); err != nil {
+				slog.Error("注销服务失败", "error", err)
+			}
+		}
 	}()
 
 	// 设置初始定时器模式
@@ -202,6 +230,13 @@ func (app *App) triggerCheck() {
 
 	if err := app.checkProxies(); err != nil {
 		slog.Error(fmt.Sprintf("检测代理失败: %v", err))
+		// os.Exit会跳过Run()里注销服务的defer，这里退出前显式注销一次；
+		// Registry.Deregister是幂等的，多处调用也只会真正注销一次。进程收到退出信号时的处理路径不调用它
+		if app.registry != nil {
+			if derr := app.registry.Deregister(); derr != nil {
+				slog.Error("退出前注销服务失败", "error", derr)
+			}
+		}
 		os.Exit(1)
 	}
 
@@ -226,10 +261,34 @@ func (app *App) triggerCheck() {
 func (app *App) checkProxies() error {
 	slog.Info("开始准备检测代理", "进度展示", config.GlobalConfig.PrintProgress)
 
+	// 先同步 git+ 形式的订阅源，拉取到的节点文件会合并进正常的订阅解析流程
+	app.syncGitSubUrls()
+
+	// 跳过仍处于退避期内的订阅URL，并把本轮同步到的git订阅源本地文件并入抓取列表
+	// （git+ 形式的原始地址本身不能直接被抓取，统一替换为其同步下来的本地文件），
+	// 检测结束后恢复完整列表（配置文件回写、健康状态展示等仍然需要看到原始订阅配置）
+	allSubUrls := config.GlobalConfig.SubUrls
+	var directSubUrls []string
+	for _, url := range proxies.FilterEligibleUrls(allSubUrls) {
+		if !strings.HasPrefix(url, "git+") {
+			directSubUrls = append(directSubUrls, url)
+		}
+	}
+
+	// check.Check()本身不会把逐个订阅URL的成败回传出来，因此这里对即将抓取的普通http(s)订阅
+	// 地址各探测一次可达性，把结果记入 proxies.RecordFetch，驱动下面 handleSubsLifecycle
+	// 里的健康评分、指数退避与自动移除；git+ 源的成败已经在 syncGitSubUrls 里单独记录过
+	app.probeSubUrls(directSubUrls)
+
+	config.GlobalConfig.SubUrls = append(directSubUrls, app.gitSyncedFiles...)
+
+	checkStart := time.Now()
 	results, err := check.Check()
+	config.GlobalConfig.SubUrls = allSubUrls
 	if err != nil {
 		return fmt.Errorf("检测代理失败: %w", err)
 	}
+	observeCheckRun(time.Since(checkStart), results)
 	// 将成功的节点添加到全局中，暂时内存保存
 	if config.GlobalConfig.KeepSuccessProxies {
 		for _, result := range results {
@@ -240,6 +299,7 @@ func (app *App) checkProxies() error {
 	}
 
 	slog.Info("检测完成")
+	app.resultHub.publish(results)
 	save.SaveConfig(results)
 	utils.SendNotify(len(results))
 	utils.UpdateSubs()
@@ -255,6 +315,125 @@ func (app *App) checkProxies() error {
 	return nil
 }
 
+// gitSourceConfigFor 返回该仓库地址对应的附加配置（凭据/sparse/独立cron），
+// 未在配置文件中声明时返回零值，此时 GitSource 退化为匿名、非sparse、跟随全局间隔的拉取
+func gitSourceConfigFor(repoURL string) config.GitSourceConfig {
+	for _, c := range config.GlobalConfig.GitSources {
+		if c.RepoURL == repoURL {
+			return c
+		}
+	}
+	return config.GitSourceConfig{}
+}
+
+// syncGitSubUrls 同步所有 "git+" 形式的订阅源，并将拉取结果并入订阅健康状态，
+// 失败的仓库会走与普通订阅相同的失败计数与自动退避/移除路径。
+// 同步得到的本地节点文件会记录到 app.gitSyncedFiles，由 checkProxies 并入本轮实际抓取的订阅列表，
+// 从而真正进入正常的订阅解析流程，而不只是被同步到本地就结束
+func (app *App) syncGitSubUrls() {
+	configDir := filepath.Dir(app.configPath)
+
+	var gitUrls []string
+	for _, raw := range config.GlobalConfig.SubUrls {
+		if strings.HasPrefix(raw, "git+") {
+			gitUrls = append(gitUrls, raw)
+		}
+	}
+	app.gitSyncedFiles = nil
+	if len(gitUrls) == 0 {
+		return
+	}
+
+	state, err := LoadSubsState(configDir)
+	if err != nil {
+		slog.Error("加载订阅状态失败", "error", err)
+		return
+	}
+
+	for _, raw := range gitUrls {
+		if !state.ShouldFetch(raw) {
+			slog.Debug("Git订阅源仍处于退避期，本轮跳过", "url", raw)
+			continue
+		}
+
+		repoURL, ref, path, ok := proxies.ParseGitSourceURL(raw)
+		if !ok {
+			slog.Warn("Git订阅源地址格式错误", "url", raw)
+			continue
+		}
+
+		srcCfg := gitSourceConfigFor(repoURL)
+		src := &proxies.GitSource{
+			Alias:   proxies.SafeAlias(repoURL),
+			RepoURL: repoURL,
+			Ref:     ref,
+			Paths:   []string{path},
+			Sparse:  srcCfg.Sparse,
+			Auth: proxies.GitSourceAuth{
+				Token:      srcCfg.Token,
+				SSHKeyPath: srcCfg.SSHKeyPath,
+			},
+			CronOverride: srcCfg.CronOverride,
+		}
+
+		var lastSync time.Time
+		if h, ok := state.Health[raw]; ok {
+			lastSync = h.LastCheckedAt
+		}
+		if !src.ShouldSync(lastSync) {
+			slog.Debug("Git订阅源未到独立调度时间，本轮跳过", "url", raw, "cron", srcCfg.CronOverride)
+			continue
+		}
+
+		start := time.Now()
+		files, err := src.Sync(configDir)
+		latency := time.Since(start)
+		if err != nil {
+			slog.Error("同步Git订阅源失败", "url", raw, "error", err)
+			state.RecordResult(raw, false, err.Error(), latency)
+			continue
+		}
+
+		state.RecordResult(raw, true, "", latency)
+		app.gitSyncedFiles = append(app.gitSyncedFiles, files...)
+		slog.Info("Git订阅源同步完成", "url", raw, "files", files)
+	}
+
+	if err := state.SaveToFile(configDir); err != nil {
+		slog.Error("保存订阅状态失败", "error", err)
+	}
+}
+
+// probeSubUrls 对本轮将要抓取的普通http(s)订阅地址并发探测一次可达性，并把结果记入
+// proxies.RecordFetch，供 handleSubsLifecycle 取出后驱动健康评分与指数退避
+func (app *App) probeSubUrls(urls []string) {
+	var wg sync.WaitGroup
+	for _, url := range urls {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			success, errMsg, latency := proxies.ProbeSubUrl(u)
+			var err error
+			if errMsg != "" {
+				err = fmt.Errorf("%s", errMsg)
+			}
+			proxies.RecordFetch(u, success, err, latency)
+		}(url)
+	}
+	wg.Wait()
+}
+
+// isSubUrlEligible 供 proxies.SetEligibilityChecker 注入，判断某个订阅URL是否已度过退避期。
+// 读取失败时默认放行，避免状态文件异常导致所有订阅被永久跳过
+func (app *App) isSubUrlEligible(url string) bool {
+	state, err := LoadSubsState(filepath.Dir(app.configPath))
+	if err != nil {
+		slog.Warn("读取订阅状态失败，跳过退避期判断", "url", url, "error", err)
+		return true
+	}
+	return state.ShouldFetch(url)
+}
+
 // handleSubsLifecycle 处理订阅生命周期管理
 func (app *App) handleSubsLifecycle() error {
 	// 如果功能被禁用，直接返回
@@ -262,10 +441,10 @@ func (app *App) handleSubsLifecycle() error {
 		return nil
 	}
 
-	// 获取本轮统计
-	successUrls, failedUrls := proxies.GetAndResetRunStats()
+	// 获取本轮统计，包含每个订阅URL的成功/失败、错误信息与抓取时延
+	fetchStats := proxies.GetAndResetRunStats()
 
-	if len(successUrls) == 0 && len(failedUrls) == 0 {
+	if len(fetchStats) == 0 {
 		return nil // 没有统计数据
 	}
 
@@ -278,16 +457,17 @@ func (app *App) handleSubsLifecycle() error {
 		return fmt.Errorf("加载订阅状态失败: %w", err)
 	}
 
-	// 更新失败计数
-	for _, url := range successUrls {
-		state.UpdateFailCount(url, false) // 成功
-	}
-	for _, url := range failedUrls {
-		state.UpdateFailCount(url, true) // 失败
+	// 更新健康记录，驱动指数退避
+	for _, stat := range fetchStats {
+		state.RecordResult(stat.Url, stat.Success, stat.Err, stat.Latency)
+		if !stat.Success {
+			observeSubFetchFailure(stat.Url)
+		}
 	}
+	observeSubsHealth(state)
 
-	// 获取需要移除的URL
-	urlsToRemove := state.GetFailedUrls(config.GlobalConfig.SubUrlsFailRemove)
+	// 获取需要移除的URL：连续失败达到阈值，且最近一次成功发生在窗口之前（或从未成功过）
+	urlsToRemove := state.GetFailedUrls(config.GlobalConfig.SubUrlsFailRemove, config.GlobalConfig.SubUrlsFailRemoveWindow)
 
 	if len(urlsToRemove) > 0 {
 		slog.Warn("发现需要移除的订阅", "count", len(urlsToRemove), "urls", urlsToRemove)
@@ -299,6 +479,7 @@ func (app *App) handleSubsLifecycle() error {
 
 		// 清理状态记录
 		state.CleanupUrls(urlsToRemove)
+		removeSubMetrics(urlsToRemove)
 
 		slog.Info("已自动移除失败订阅", "count", len(urlsToRemove))
 	}
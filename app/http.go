@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+
+	"github.com/beck-8/subs-check/config"
+)
+
+// initHttpServer 初始化HTTP服务器，并注册各API路由
+func (app *App) initHttpServer() error {
+	mux := http.NewServeMux()
+	app.mux = mux
+
+	mux.HandleFunc("/api/subs/health", app.handleSubsHealth)
+	mux.HandleFunc("/api/results", app.handleResultsList)
+	mux.HandleFunc("/api/results/watch", app.handleResultsWatch)
+	mux.HandleFunc("/metrics", app.handleMetrics)
+	mux.HandleFunc("/api/shell", app.handleWebShell)
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%s", config.GlobalConfig.ListenPort),
+		Handler: mux,
+	}
+	app.httpServer = server
+
+	go func() {
+		slog.Info("HTTP服务器启动", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("HTTP服务器异常退出", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// handleSubsHealth 返回各订阅URL的健康快照
+func (app *App) handleSubsHealth(w http.ResponseWriter, r *http.Request) {
+	configDir := filepath.Dir(app.configPath)
+	state, err := LoadSubsState(configDir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("加载订阅状态失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(state.Snapshot()); err != nil {
+		slog.Error("编码订阅健康信息失败", "error", err)
+	}
+}
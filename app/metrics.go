@@ -0,0 +1,126 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/beck-8/subs-check/check"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics 汇总了本包向 Prometheus 暴露的全部指标，统一注册、统一使用
+var metrics = struct {
+	checkRunsTotal   prometheus.Counter
+	checkDurationSec prometheus.Histogram
+	proxiesAlive     *prometheus.GaugeVec
+	proxyLatencyMs   prometheus.Histogram
+	subFetchFailures *prometheus.CounterVec
+	subFailCount     *prometheus.GaugeVec
+}{
+	checkRunsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "subscheck_check_runs_total",
+		Help: "检测轮次累计执行次数",
+	}),
+	checkDurationSec: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "subscheck_check_duration_seconds",
+		Help:    "单轮检测耗时分布",
+		Buckets: prometheus.DefBuckets,
+	}),
+	proxiesAlive: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "subscheck_proxies_alive",
+		Help: "最近一轮检测中存活的代理数量",
+	}, []string{"protocol", "country"}),
+	proxyLatencyMs: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "subscheck_proxy_latency_ms",
+		Help:    "单个代理检测延迟分布（毫秒）",
+		Buckets: prometheus.ExponentialBuckets(10, 2, 12),
+	}),
+	subFetchFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscheck_sub_fetch_failures_total",
+		Help: "订阅源抓取失败累计次数",
+	}, []string{"url"}),
+	subFailCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "subscheck_sub_fail_count",
+		Help: "订阅源当前连续失败次数",
+	}, []string{"url"}),
+}
+
+func init() {
+	prometheus.MustRegister(
+		metrics.checkRunsTotal,
+		metrics.checkDurationSec,
+		metrics.proxiesAlive,
+		metrics.proxyLatencyMs,
+		metrics.subFetchFailures,
+		metrics.subFailCount,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// observeCheckRun 记录一轮检测的耗时与结果分布
+func observeCheckRun(duration time.Duration, results []check.Result) {
+	metrics.checkRunsTotal.Inc()
+	metrics.checkDurationSec.Observe(duration.Seconds())
+
+	metrics.proxiesAlive.Reset()
+	counts := make(map[[2]string]int)
+	for _, r := range results {
+		if r.Proxy == nil {
+			continue
+		}
+		key := [2]string{r.Proxy.GetType(), r.Proxy.GetCountry()}
+		counts[key]++
+		metrics.proxyLatencyMs.Observe(float64(r.Latency.Milliseconds()))
+	}
+	for key, count := range counts {
+		metrics.proxiesAlive.WithLabelValues(key[0], key[1]).Set(float64(count))
+	}
+}
+
+// subUrlMetricLabel 将订阅URL转换为可安全暴露在 /metrics 上的标签值：去掉userinfo与query
+// （私有订阅常把token放在这两处），只保留scheme+host+path用于辨识，不直接使用原始URL作为标签，
+// 避免抓取 /metrics 的任何人都能拿到订阅凭据
+func subUrlMetricLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		sum := sha256.Sum256([]byte(rawURL))
+		return hex.EncodeToString(sum[:8])
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// observeSubsHealth 将订阅健康状态同步到 Prometheus 指标，镜像 SubsState.Health。
+// 每次都先Reset掉失效的gauge系列，避免订阅被移除后其series在/metrics里永久残留
+func observeSubsHealth(state *SubsState) {
+	metrics.subFailCount.Reset()
+	for rawURL, h := range state.Health {
+		label := subUrlMetricLabel(rawURL)
+		metrics.subFailCount.WithLabelValues(label).Set(float64(h.ConsecutiveFails))
+	}
+}
+
+// observeSubFetchFailure 记录一次订阅抓取失败
+func observeSubFetchFailure(rawURL string) {
+	metrics.subFetchFailures.WithLabelValues(subUrlMetricLabel(rawURL)).Inc()
+}
+
+// removeSubMetrics 在订阅URL被自动移除后，一并清理其在 subFetchFailures 上残留的累计计数系列
+func removeSubMetrics(urls []string) {
+	for _, rawURL := range urls {
+		metrics.subFetchFailures.DeleteLabelValues(subUrlMetricLabel(rawURL))
+	}
+}
+
+// handleMetrics 是 /metrics 的处理函数，委托给 promhttp 的标准实现
+func (app *App) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	promhttp.Handler().ServeHTTP(w, r)
+}
@@ -0,0 +1,315 @@
+// Package registry 提供可选的服务注册与动态订阅发现能力，
+// 让 subs-check 可以作为集群中的一个组件，由控制面统一下发订阅列表。
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdKeepAliveRetryInterval 是重新注册失败后，重试前的等待时长
+const etcdKeepAliveRetryInterval = 5 * time.Second
+
+// Backend 标识使用的注册中心类型
+type Backend string
+
+const (
+	BackendConsul Backend = "consul"
+	BackendEtcd   Backend = "etcd"
+)
+
+// Config 是 registry 模块的配置，对应 YAML 中的 registry 配置块
+type Config struct {
+	Enabled     bool          `yaml:"enabled"`
+	Backend     Backend       `yaml:"backend"`
+	Address     string        `yaml:"address"` // consul地址或etcd endpoints（逗号分隔）
+	Token       string        `yaml:"token"`   // consul acl token / etcd auth token，可选
+	ServiceName string        `yaml:"service-name"`
+	ServiceTag  string        `yaml:"service-tag"`
+	Port        int           `yaml:"port"`
+	HealthPath  string        `yaml:"health-path"`  // 健康检查路径，默认 /health
+	KVPrefix    string        `yaml:"kv-prefix"`    // 动态订阅列表前缀，默认 subs-check/sub-urls/
+	WatchPeriod time.Duration `yaml:"watch-period"` // consul KV阻塞查询的最长等待时间，默认30s；etcd监听不使用该值
+}
+
+// splitEndpoints 将逗号分隔的etcd endpoints地址拆分为slice
+func splitEndpoints(address string) []string {
+	var endpoints []string
+	for _, ep := range strings.Split(address, ",") {
+		ep = strings.TrimSpace(ep)
+		if ep != "" {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints
+}
+
+// SubUrlsChangeFunc 在动态订阅列表发生变化时被调用，传入当前KV前缀下的全部订阅URL
+type SubUrlsChangeFunc func(urls []string)
+
+// Registry 管理服务注册与动态订阅发现的生命周期
+type Registry struct {
+	cfg       Config
+	consul    *consulapi.Client
+	etcd      *clientv3.Client
+	serviceID string
+
+	cancelWatch context.CancelFunc
+	wg          sync.WaitGroup
+
+	stopCh         chan struct{}
+	deregisterOnce sync.Once
+	deregisterErr  error
+}
+
+// New 根据配置创建对应后端的 Registry，Enabled=false 时返回 nil；
+// Enabled=true 而 cfg.Backend 既不是 consul 也不是 etcd（包括为空）时返回错误
+func New(cfg Config) (*Registry, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.HealthPath == "" {
+		cfg.HealthPath = "/health"
+	}
+	if cfg.KVPrefix == "" {
+		cfg.KVPrefix = "subs-check/sub-urls/"
+	}
+	if cfg.WatchPeriod == 0 {
+		cfg.WatchPeriod = 30 * time.Second
+	}
+
+	r := &Registry{cfg: cfg, stopCh: make(chan struct{})}
+
+	switch cfg.Backend {
+	case BackendConsul:
+		client, err := consulapi.NewClient(&consulapi.Config{Address: cfg.Address, Token: cfg.Token})
+		if err != nil {
+			return nil, fmt.Errorf("创建consul客户端失败: %w", err)
+		}
+		r.consul = client
+	case BackendEtcd:
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   splitEndpoints(cfg.Address),
+			DialTimeout: 5 * time.Second,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("创建etcd客户端失败: %w", err)
+		}
+		r.etcd = client
+	default:
+		return nil, fmt.Errorf("不支持的注册中心类型: %s", cfg.Backend)
+	}
+
+	return r, nil
+}
+
+// Register 向注册中心注册当前实例，健康检查命中本进程的 /health 接口
+func (r *Registry) Register() error {
+	if r == nil {
+		return nil
+	}
+
+	r.serviceID = fmt.Sprintf("%s-%d", r.cfg.ServiceName, os.Getpid())
+
+	switch r.cfg.Backend {
+	case BackendConsul:
+		reg := &consulapi.AgentServiceRegistration{
+			ID:   r.serviceID,
+			Name: r.cfg.ServiceName,
+			Port: r.cfg.Port,
+			Tags: []string{r.cfg.ServiceTag},
+			Check: &consulapi.AgentServiceCheck{
+				HTTP:     fmt.Sprintf("http://127.0.0.1:%d%s", r.cfg.Port, r.cfg.HealthPath),
+				Interval: "10s",
+				Timeout:  "3s",
+			},
+		}
+		if err := r.consul.Agent().ServiceRegister(reg); err != nil {
+			return fmt.Errorf("注册到consul失败: %w", err)
+		}
+		slog.Info("已注册到consul", "service", r.cfg.ServiceName, "id", r.serviceID)
+	case BackendEtcd:
+		lease, err := r.etcd.Grant(context.Background(), 30)
+		if err != nil {
+			return fmt.Errorf("创建etcd租约失败: %w", err)
+		}
+		key := fmt.Sprintf("services/%s/%s", r.cfg.ServiceName, r.serviceID)
+		if _, err := r.etcd.Put(context.Background(), key, fmt.Sprintf("127.0.0.1:%d", r.cfg.Port), clientv3.WithLease(lease.ID)); err != nil {
+			return fmt.Errorf("注册到etcd失败: %w", err)
+		}
+		keepAliveCh, err := r.etcd.KeepAlive(context.Background(), lease.ID)
+		if err != nil {
+			return fmt.Errorf("启动etcd租约续期失败: %w", err)
+		}
+		r.wg.Add(1)
+		go r.runEtcdKeepAlive(keepAliveCh)
+		slog.Info("已注册到etcd", "service", r.cfg.ServiceName, "id", r.serviceID)
+	}
+
+	return nil
+}
+
+// runEtcdKeepAlive 持续消费租约续期响应；一旦channel关闭（租约到期未续上，或与etcd失联），
+// 说明etcd已经把本实例摘除了，这里必须重新注册并log出来，否则就是悄无声息地永久失联。
+// Deregister被调用（stopCh关闭）时退出，不再重试
+func (r *Registry) runEtcdKeepAlive(keepAliveCh <-chan *clientv3.LeaseKeepAliveResponse) {
+	defer r.wg.Done()
+
+	for {
+		for range keepAliveCh {
+			// 消费续期响应，保持租约存活
+		}
+
+		select {
+		case <-r.stopCh:
+			return
+		default:
+		}
+
+		slog.Warn("etcd租约续期通道已关闭，本实例可能已被etcd摘除，尝试重新注册", "service", r.cfg.ServiceName, "id", r.serviceID)
+		newCh, err := r.reRegisterEtcd()
+		if err != nil {
+			slog.Error("重新注册到etcd失败，稍后重试", "error", err)
+			select {
+			case <-time.After(etcdKeepAliveRetryInterval):
+			case <-r.stopCh:
+				return
+			}
+			continue
+		}
+		keepAliveCh = newCh
+	}
+}
+
+// reRegisterEtcd 重新申请租约并写回服务key，返回新的keepalive通道供调用方继续消费
+func (r *Registry) reRegisterEtcd() (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	lease, err := r.etcd.Grant(context.Background(), 30)
+	if err != nil {
+		return nil, fmt.Errorf("重新创建etcd租约失败: %w", err)
+	}
+	key := fmt.Sprintf("services/%s/%s", r.cfg.ServiceName, r.serviceID)
+	if _, err := r.etcd.Put(context.Background(), key, fmt.Sprintf("127.0.0.1:%d", r.cfg.Port), clientv3.WithLease(lease.ID)); err != nil {
+		return nil, fmt.Errorf("重新注册到etcd失败: %w", err)
+	}
+	keepAliveCh, err := r.etcd.KeepAlive(context.Background(), lease.ID)
+	if err != nil {
+		return nil, fmt.Errorf("重新启动etcd租约续期失败: %w", err)
+	}
+	slog.Info("已重新注册到etcd", "service", r.cfg.ServiceName, "id", r.serviceID)
+	return keepAliveCh, nil
+}
+
+// Deregister 从注册中心移除当前实例。幂等：无论被调用方（app.Run的defer、
+// 检测失败后的os.Exit前清理等）调用多少次，只会真正执行一次，避免重复注销触发的错误或竞态。
+// 进程信号处理路径不调用它
+func (r *Registry) Deregister() error {
+	if r == nil {
+		return nil
+	}
+
+	r.deregisterOnce.Do(func() {
+		r.deregisterErr = r.doDeregister()
+		close(r.stopCh)
+	})
+	return r.deregisterErr
+}
+
+func (r *Registry) doDeregister() error {
+	switch r.cfg.Backend {
+	case BackendConsul:
+		if err := r.consul.Agent().ServiceDeregister(r.serviceID); err != nil {
+			return fmt.Errorf("从consul注销失败: %w", err)
+		}
+	case BackendEtcd:
+		key := fmt.Sprintf("services/%s/%s", r.cfg.ServiceName, r.serviceID)
+		if _, err := r.etcd.Delete(context.Background(), key); err != nil {
+			return fmt.Errorf("从etcd注销失败: %w", err)
+		}
+	}
+
+	if r.cancelWatch != nil {
+		r.cancelWatch()
+	}
+	slog.Info("已从注册中心注销", "service", r.cfg.ServiceName, "id", r.serviceID)
+	return nil
+}
+
+// WatchSubUrls 监听 KVPrefix 下的订阅列表变化，每次变化都会以该前缀下的全部值回调 onChange，
+// 调用方负责将其与文件中的 sub-urls 合并后写回 config.GlobalConfig.SubUrls
+func (r *Registry) WatchSubUrls(onChange SubUrlsChangeFunc) {
+	if r == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancelWatch = cancel
+
+	switch r.cfg.Backend {
+	case BackendConsul:
+		go r.watchConsulKV(ctx, onChange)
+	case BackendEtcd:
+		go r.watchEtcdKV(ctx, onChange)
+	}
+}
+
+func (r *Registry) watchConsulKV(ctx context.Context, onChange SubUrlsChangeFunc) {
+	var lastIndex uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		pairs, meta, err := r.consul.KV().List(r.cfg.KVPrefix, &consulapi.QueryOptions{
+			WaitIndex: lastIndex,
+			WaitTime:  r.cfg.WatchPeriod,
+		})
+		if err != nil {
+			slog.Warn("监听consul KV失败，稍后重试", "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		lastIndex = meta.LastIndex
+
+		urls := make([]string, 0, len(pairs))
+		for _, p := range pairs {
+			urls = append(urls, string(p.Value))
+		}
+		onChange(urls)
+	}
+}
+
+func (r *Registry) watchEtcdKV(ctx context.Context, onChange SubUrlsChangeFunc) {
+	resp, err := r.etcd.Get(ctx, r.cfg.KVPrefix, clientv3.WithPrefix())
+	if err == nil {
+		onChange(etcdValues(resp.Kvs))
+	}
+
+	watchCh := r.etcd.Watch(ctx, r.cfg.KVPrefix, clientv3.WithPrefix())
+	for range watchCh {
+		resp, err := r.etcd.Get(ctx, r.cfg.KVPrefix, clientv3.WithPrefix())
+		if err != nil {
+			slog.Warn("刷新etcd KV失败", "error", err)
+			continue
+		}
+		onChange(etcdValues(resp.Kvs))
+	}
+}
+
+func etcdValues(kvs []*mvccpb.KeyValue) []string {
+	urls := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		urls = append(urls, string(kv.Value))
+	}
+	return urls
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/beck-8/subs-check/app/registry"
+	"github.com/beck-8/subs-check/config"
+)
+
+// dynamicSubUrls 保存从注册中心动态发现的订阅列表，与文件中的 sub-urls 合并后
+// 一并提供给检测流程，互斥锁保护并发的watch回调与读取
+var dynamicSubUrls struct {
+	mu   sync.RWMutex
+	urls []string
+}
+
+// initRegistry 按配置决定是否启用 Consul/etcd 服务注册与动态订阅发现
+func (app *App) initRegistry() error {
+	cfg := config.GlobalConfig.Registry
+	if !cfg.Enabled {
+		return nil
+	}
+
+	port, err := strconv.Atoi(config.GlobalConfig.ListenPort)
+	if err != nil {
+		return fmt.Errorf("解析监听端口失败，服务注册需要有效的listen-port: %w", err)
+	}
+
+	reg, err := registry.New(registry.Config{
+		Enabled:     cfg.Enabled,
+		Backend:     registry.Backend(cfg.Backend),
+		Address:     cfg.Address,
+		Token:       cfg.Token,
+		ServiceName: cfg.ServiceName,
+		ServiceTag:  cfg.ServiceTag,
+		Port:        port,
+		HealthPath:  cfg.HealthPath,
+		KVPrefix:    cfg.KVPrefix,
+	})
+	if err != nil {
+		return fmt.Errorf("创建服务注册客户端失败: %w", err)
+	}
+
+	if err := reg.Register(); err != nil {
+		return fmt.Errorf("注册服务失败: %w", err)
+	}
+
+	reg.WatchSubUrls(func(urls []string) {
+		dynamicSubUrls.mu.Lock()
+		dynamicSubUrls.urls = urls
+		dynamicSubUrls.mu.Unlock()
+		slog.Info("动态订阅列表已更新", "count", len(urls))
+		app.mergeDynamicSubUrls()
+	})
+
+	app.registry = reg
+	return nil
+}
+
+// mergeDynamicSubUrls 将注册中心下发的订阅URL与配置文件中的 sub-urls 合并，去重后
+// 写回 config.GlobalConfig.SubUrls，供下一轮检测使用。
+//
+// 必须以 app.fileSubUrls（加载配置文件时的不可变快照）而不是当前的
+// config.GlobalConfig.SubUrls 作为合并基线 —— 后者是上一次合并后的结果，如果拿它当基线，
+// 动态新增的URL会在下一次合并时被当作"文件里本来就有的"，一旦控制面从监听前缀中移除该URL，
+// 它也不会再从 SubUrls 里消失，变成只能新增、无法移除
+func (app *App) mergeDynamicSubUrls() {
+	dynamicSubUrls.mu.RLock()
+	dynamic := append([]string(nil), dynamicSubUrls.urls...)
+	dynamicSubUrls.mu.RUnlock()
+
+	seen := make(map[string]struct{}, len(app.fileSubUrls)+len(dynamic))
+	merged := make([]string, 0, len(app.fileSubUrls)+len(dynamic))
+	for _, url := range app.fileSubUrls {
+		if _, ok := seen[url]; ok {
+			continue
+		}
+		seen[url] = struct{}{}
+		merged = append(merged, url)
+	}
+	for _, url := range dynamic {
+		if _, ok := seen[url]; ok {
+			continue
+		}
+		seen[url] = struct{}{}
+		merged = append(merged, url)
+	}
+
+	config.GlobalConfig.SubUrls = merged
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/beck-8/subs-check/check"
+)
+
+const (
+	// resultEventBufferSize 环形缓冲区保留的最大事件数，决定重连客户端能回放多久的增量
+	resultEventBufferSize = 1024
+)
+
+// ResultEventType 描述一次结果变更的类型，语义上与 Kubernetes informer 的事件类型一致
+type ResultEventType string
+
+const (
+	ResultEventAdded    ResultEventType = "ADDED"
+	ResultEventModified ResultEventType = "MODIFIED"
+	ResultEventDeleted  ResultEventType = "DELETED"
+)
+
+// ResultEvent 是推送给订阅方的一条增量事件
+type ResultEvent struct {
+	Type            ResultEventType `json:"type"`
+	Proxy           check.Result    `json:"proxy"`
+	ResourceVersion uint64          `json:"resourceVersion"`
+}
+
+// resultWatchHub 维护最近一轮检测结果的全量快照与增量事件环形缓冲区，
+// 供 /api/results 与 /api/results/watch 两个接口复用
+type resultWatchHub struct {
+	mu              sync.RWMutex
+	resourceVersion uint64
+	current         map[string]check.Result // 以代理名作为key的当前快照
+	events          []ResultEvent           // 环形缓冲区，保存最近的增量事件
+	subscribers     map[chan ResultEvent]struct{}
+}
+
+func newResultWatchHub() *resultWatchHub {
+	return &resultWatchHub{
+		current:     make(map[string]check.Result),
+		subscribers: make(map[chan ResultEvent]struct{}),
+	}
+}
+
+// publish 在每次 checkProxies 完成一轮检测后调用，对比新旧快照生成 ADDED/MODIFIED/DELETED 事件
+func (h *resultWatchHub) publish(results []check.Result) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	next := make(map[string]check.Result, len(results))
+	for _, r := range results {
+		// 失败的检测结果 Proxy 为 nil（与 app.go checkProxies 中的判断一致），
+		// 这类结果没有代理身份可言，不参与增量对比
+		if r.Proxy == nil {
+			continue
+		}
+		next[r.Proxy.GetName()] = r
+	}
+
+	var events []ResultEvent
+	for name, r := range next {
+		if old, ok := h.current[name]; !ok {
+			events = append(events, ResultEvent{Type: ResultEventAdded, Proxy: r})
+		} else if !old.Equal(r) {
+			events = append(events, ResultEvent{Type: ResultEventModified, Proxy: r})
+		}
+	}
+	for name, r := range h.current {
+		if _, ok := next[name]; !ok {
+			events = append(events, ResultEvent{Type: ResultEventDeleted, Proxy: r})
+		}
+	}
+
+	h.current = next
+	for i := range events {
+		h.resourceVersion++
+		events[i].ResourceVersion = h.resourceVersion
+		h.appendEvent(events[i])
+	}
+
+	for ch := range h.subscribers {
+		for _, ev := range events {
+			select {
+			case ch <- ev:
+			default:
+				slog.Warn("watch订阅者消费过慢，丢弃事件", "resourceVersion", ev.ResourceVersion)
+			}
+		}
+	}
+}
+
+// appendEvent 将事件写入环形缓冲区，超出容量时丢弃最旧的事件
+func (h *resultWatchHub) appendEvent(ev ResultEvent) {
+	h.events = append(h.events, ev)
+	if len(h.events) > resultEventBufferSize {
+		h.events = h.events[len(h.events)-resultEventBufferSize:]
+	}
+}
+
+// replaySince 返回 resourceVersion 之后发生的事件；若请求的版本过旧、已被缓冲区淘汰则返回 ok=false，
+// 调用方应提示客户端退回全量 list
+func (h *resultWatchHub) replaySince(resourceVersion uint64) (events []ResultEvent, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.events) == 0 {
+		return nil, resourceVersion == h.resourceVersion
+	}
+	oldest := h.events[0].ResourceVersion
+	if resourceVersion < oldest-1 {
+		return nil, false
+	}
+
+	for _, ev := range h.events {
+		if ev.ResourceVersion > resourceVersion {
+			events = append(events, ev)
+		}
+	}
+	return events, true
+}
+
+// list 返回当前全量快照以及对应的 resourceVersion
+func (h *resultWatchHub) list() ([]check.Result, uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	results := make([]check.Result, 0, len(h.current))
+	for _, r := range h.current {
+		results = append(results, r)
+	}
+	return results, h.resourceVersion
+}
+
+// subscribe 注册一个事件订阅通道，返回的 cancel 函数用于客户端断开时清理
+func (h *resultWatchHub) subscribe() (ch chan ResultEvent, cancel func()) {
+	ch = make(chan ResultEvent, 128)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subscribers, ch)
+		h.mu.Unlock()
+		close(ch)
+	}
+}
+
+// handleResultsList 处理 GET /api/results，返回当前全量结果快照
+func (app *App) handleResultsList(w http.ResponseWriter, r *http.Request) {
+	results, resourceVersion := app.resultHub.list()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Resource-Version", strconv.FormatUint(resourceVersion, 10))
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		slog.Error("编码检测结果失败", "error", err)
+	}
+}
+
+// handleResultsWatch 处理 GET /api/results/watch?resourceVersion=N，以 JSON Lines 的形式
+// 持续推送增量事件；客户端携带上次收到的 resourceVersion 重连时，会先收到缺失的历史增量，
+// 避免每次都拉取全量结果
+func (app *App) handleResultsWatch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "服务器不支持流式响应", http.StatusInternalServerError)
+		return
+	}
+
+	var since uint64
+	if v := r.URL.Query().Get("resourceVersion"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "resourceVersion参数非法", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+
+	backlog, ok := app.resultHub.replaySince(since)
+	if !ok {
+		// 请求的版本过旧，缓冲区已无法回放，提示客户端退回全量list
+		encoder.Encode(map[string]string{"error": "resourceVersion太旧，请重新GET /api/results"})
+		flusher.Flush()
+		return
+	}
+	for _, ev := range backlog {
+		if err := encoder.Encode(ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ch, cancel := app.resultHub.subscribe()
+	defer cancel()
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import "testing"
+
+func TestReplaySinceBoundary(t *testing.T) {
+	h := newResultWatchHub()
+
+	for i := uint64(1); i <= 3; i++ {
+		h.resourceVersion = i
+		h.appendEvent(ResultEvent{Type: ResultEventAdded, ResourceVersion: i})
+	}
+
+	if events, ok := h.replaySince(0); !ok || len(events) != 3 {
+		t.Fatalf("从0开始应回放全部3个事件, got %d events, ok=%v", len(events), ok)
+	}
+
+	if events, ok := h.replaySince(1); !ok || len(events) != 2 {
+		t.Fatalf("从1开始应回放2个事件, got %d events, ok=%v", len(events), ok)
+	}
+
+	if events, ok := h.replaySince(3); !ok || len(events) != 0 {
+		t.Fatalf("已是最新版本不应有增量事件, got %d events, ok=%v", len(events), ok)
+	}
+}
+
+func TestReplaySinceTooOld(t *testing.T) {
+	h := newResultWatchHub()
+
+	total := uint64(resultEventBufferSize + 10)
+	for i := uint64(1); i <= total; i++ {
+		h.resourceVersion = i
+		h.appendEvent(ResultEvent{Type: ResultEventAdded, ResourceVersion: i})
+	}
+
+	oldest := h.events[0].ResourceVersion
+
+	if _, ok := h.replaySince(oldest - 2); ok {
+		t.Fatalf("请求早于缓冲区最旧事件的版本应返回ok=false")
+	}
+	if _, ok := h.replaySince(oldest - 1); !ok {
+		t.Fatalf("请求版本恰好等于oldest-1（代表客户端没有丢失任何事件）时应仍可回放")
+	}
+}
@@ -4,111 +4,273 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"time"
 )
 
+const (
+	// backoffBase 退避基础时长
+	backoffBase = time.Minute
+	// backoffCap 退避时长上限
+	backoffCap = 12 * time.Hour
+	// emaAlpha 时延EMA平滑系数
+	emaAlpha = 0.3
+	// subUrlsFailRemoveDefaultWindow 是 config.SubUrlsFailRemoveWindow 未配置（<=0）时使用的默认窗口：
+	// 超过这个时长没有任何一次成功，才允许把"曾经成功过"的订阅计入自动移除，避免长期存活的订阅
+	// 因为历史上成功过一次就永久豁免
+	subUrlsFailRemoveDefaultWindow = 7 * 24 * time.Hour
+)
+
+// UrlHealth 记录单个订阅URL的健康状态
+type UrlHealth struct {
+	ConsecutiveFails int       `json:"consecutive_fails"`
+	TotalFails       int       `json:"total_fails"`
+	TotalSuccess     int       `json:"total_success"`
+	LastError        string    `json:"last_error,omitempty"`
+	LastCheckedAt    time.Time `json:"last_checked_at,omitempty"`
+	LastSuccessAt    time.Time `json:"last_success_at,omitempty"`
+	FirstFailedAt    time.Time `json:"first_failed_at,omitempty"`
+	NextEligibleAt   time.Time `json:"next_eligible_at,omitempty"`
+	DisabledUntil    time.Time `json:"disabled_until,omitempty"`
+	EMALatencyMs     float64   `json:"ema_latency_ms,omitempty"`
+}
+
+// HealthScore 综合成功率、近期失败情况与延迟计算出的健康分数，取值范围[0, 100]
+func (h *UrlHealth) HealthScore() float64 {
+	total := h.TotalSuccess + h.TotalFails
+	if total == 0 {
+		return 100
+	}
+
+	successRatio := float64(h.TotalSuccess) / float64(total)
+
+	// 连续失败次数越多，惩罚越大（指数衰减）
+	recencyPenalty := 1 - math.Pow(0.8, float64(h.ConsecutiveFails))
+
+	// 时延越高，惩罚越大，5秒视为满惩罚
+	latencyPenalty := math.Min(h.EMALatencyMs/5000, 1)
+
+	score := 100 * successRatio * (1 - 0.6*recencyPenalty) * (1 - 0.2*latencyPenalty)
+	if score < 0 {
+		score = 0
+	}
+	return score
+}
+
+// IsEligible 判断该URL当前是否已度过退避期，可以被抓取
+func (h *UrlHealth) IsEligible(now time.Time) bool {
+	if h.NextEligibleAt.IsZero() {
+		return true
+	}
+	return !now.Before(h.NextEligibleAt)
+}
+
 // SubsState 订阅状态管理
 type SubsState struct {
-	FailCounts map[string]int `json:"fail_counts"`
+	Health map[string]*UrlHealth `json:"health"`
 }
 
 // NewSubsState 创建新的订阅状态
 func NewSubsState() *SubsState {
 	return &SubsState{
-		FailCounts: make(map[string]int),
+		Health: make(map[string]*UrlHealth),
 	}
 }
 
 // LoadSubsState 从文件加载订阅状态
 func LoadSubsState(configDir string) (*SubsState, error) {
 	statePath := filepath.Join(configDir, "subs_state.json")
-	
+
 	// 如果文件不存在，返回新的状态
 	if _, err := os.Stat(statePath); os.IsNotExist(err) {
 		slog.Debug("订阅状态文件不存在，创建新状态")
 		return NewSubsState(), nil
 	}
-	
+
 	data, err := os.ReadFile(statePath)
 	if err != nil {
 		return nil, fmt.Errorf("读取订阅状态文件失败: %w", err)
 	}
-	
+
 	var state SubsState
 	if err := json.Unmarshal(data, &state); err != nil {
 		slog.Warn("解析订阅状态文件失败，创建新状态", "error", err)
 		return NewSubsState(), nil
 	}
-	
-	// 确保 FailCounts 不为 nil
-	if state.FailCounts == nil {
-		state.FailCounts = make(map[string]int)
+
+	// 确保 Health 不为 nil
+	if state.Health == nil {
+		state.Health = make(map[string]*UrlHealth)
 	}
-	
+
 	return &state, nil
 }
 
 // SaveToFile 保存状态到文件
 func (s *SubsState) SaveToFile(configDir string) error {
 	statePath := filepath.Join(configDir, "subs_state.json")
-	
+
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化订阅状态失败: %w", err)
 	}
-	
+
 	if err := os.WriteFile(statePath, data, 0644); err != nil {
 		return fmt.Errorf("写入订阅状态文件失败: %w", err)
 	}
-	
+
 	return nil
 }
 
-// UpdateFailCount 更新订阅的失败计数
-func (s *SubsState) UpdateFailCount(url string, failed bool) {
-	if failed {
-		s.FailCounts[url]++
-		slog.Debug("订阅失败计数更新", "url", url, "count", s.FailCounts[url])
-	} else {
-		// 成功时清零计数
-		if s.FailCounts[url] > 0 {
-			slog.Debug("订阅成功，清零失败计数", "url", url)
+// entry 返回url对应的健康记录，不存在则创建
+func (s *SubsState) entry(url string) *UrlHealth {
+	h, ok := s.Health[url]
+	if !ok {
+		h = &UrlHealth{}
+		s.Health[url] = h
+	}
+	return h
+}
+
+// RecordResult 根据本轮抓取结果更新订阅的健康记录，并驱动指数退避
+func (s *SubsState) RecordResult(url string, success bool, errMsg string, latency time.Duration) {
+	h := s.entry(url)
+	now := time.Now()
+	h.LastCheckedAt = now
+
+	if latency > 0 {
+		latencyMs := float64(latency.Milliseconds())
+		if h.EMALatencyMs == 0 {
+			h.EMALatencyMs = latencyMs
+		} else {
+			h.EMALatencyMs = emaAlpha*latencyMs + (1-emaAlpha)*h.EMALatencyMs
 		}
-		s.FailCounts[url] = 0
 	}
+
+	if success {
+		h.TotalSuccess++
+		h.LastSuccessAt = now
+		if h.ConsecutiveFails > 0 {
+			slog.Debug("订阅恢复成功，清零连续失败计数", "url", url)
+		}
+		h.ConsecutiveFails = 0
+		h.LastError = ""
+		h.FirstFailedAt = time.Time{}
+		h.NextEligibleAt = time.Time{}
+		h.DisabledUntil = time.Time{}
+		return
+	}
+
+	h.TotalFails++
+	h.ConsecutiveFails++
+	h.LastError = errMsg
+	if h.FirstFailedAt.IsZero() {
+		h.FirstFailedAt = now
+	}
+
+	// 指数退避: min(base*2^fails, cap)
+	backoff := time.Duration(float64(backoffBase) * math.Pow(2, float64(h.ConsecutiveFails)))
+	if backoff > backoffCap {
+		backoff = backoffCap
+	}
+	h.NextEligibleAt = now.Add(backoff)
+	h.DisabledUntil = h.NextEligibleAt
+	slog.Debug("订阅抓取失败，进入退避", "url", url, "consecutive_fails", h.ConsecutiveFails, "next_eligible_at", h.NextEligibleAt)
+}
+
+// UpdateFailCount 更新订阅的失败计数，兼容旧版调用方式（不带错误信息与时延）
+func (s *SubsState) UpdateFailCount(url string, failed bool) {
+	s.RecordResult(url, !failed, "", 0)
 }
 
-// GetFailedUrls 获取失败次数超过阈值的URL列表
-func (s *SubsState) GetFailedUrls(threshold int) []string {
+// ShouldFetch 判断该URL是否已度过退避期，可以在本轮被抓取
+func (s *SubsState) ShouldFetch(url string) bool {
+	h, ok := s.Health[url]
+	if !ok {
+		return true
+	}
+	return h.IsEligible(time.Now())
+}
+
+// GetFailedUrls 获取满足移除条件的URL列表：连续失败次数达到阈值，且最近一次成功发生在
+// window之前（或从未成功过）。window<=0时使用 subUrlsFailRemoveDefaultWindow ——
+// TotalSuccess是生命周期累计计数，没有window的话曾经成功过一次的订阅就会被永久豁免于自动移除，
+// 哪怕那次成功已经是几个月前的事了
+func (s *SubsState) GetFailedUrls(threshold int, window time.Duration) []string {
 	if threshold <= 0 {
 		return nil
 	}
-	
+	if window <= 0 {
+		window = subUrlsFailRemoveDefaultWindow
+	}
+
+	now := time.Now()
 	var failedUrls []string
-	for url, count := range s.FailCounts {
-		if count >= threshold {
-			failedUrls = append(failedUrls, url)
+	for url, h := range s.Health {
+		if h.ConsecutiveFails < threshold {
+			continue
+		}
+		if !h.LastSuccessAt.IsZero() && now.Sub(h.LastSuccessAt) < window {
+			continue
 		}
+		failedUrls = append(failedUrls, url)
 	}
-	
+
 	return failedUrls
 }
 
 // CleanupUrls 清理指定URL的状态记录
 func (s *SubsState) CleanupUrls(urls []string) {
 	for _, url := range urls {
-		delete(s.FailCounts, url)
+		delete(s.Health, url)
 		slog.Debug("清理订阅状态记录", "url", url)
 	}
 }
 
-// GetFailCount 获取指定URL的失败次数
+// GetFailCount 获取指定URL的连续失败次数
 func (s *SubsState) GetFailCount(url string) int {
-	return s.FailCounts[url]
+	h, ok := s.Health[url]
+	if !ok {
+		return 0
+	}
+	return h.ConsecutiveFails
 }
 
 // GetTotalUrls 获取状态中记录的URL总数
 func (s *SubsState) GetTotalUrls() int {
-	return len(s.FailCounts)
+	return len(s.Health)
+}
+
+// HealthSnapshot 是对外展示的健康信息，供 /api/subs/health 使用
+type HealthSnapshot struct {
+	Url              string    `json:"url"`
+	Score            float64   `json:"score"`
+	ConsecutiveFails int       `json:"consecutive_fails"`
+	TotalFails       int       `json:"total_fails"`
+	TotalSuccess     int       `json:"total_success"`
+	LastError        string    `json:"last_error,omitempty"`
+	LastCheckedAt    time.Time `json:"last_checked_at,omitempty"`
+	NextEligibleAt   time.Time `json:"next_eligible_at,omitempty"`
+	EMALatencyMs     float64   `json:"ema_latency_ms,omitempty"`
+}
+
+// Snapshot 生成所有订阅URL的健康快照，用于健康检查接口
+func (s *SubsState) Snapshot() []HealthSnapshot {
+	snapshots := make([]HealthSnapshot, 0, len(s.Health))
+	for url, h := range s.Health {
+		snapshots = append(snapshots, HealthSnapshot{
+			Url:              url,
+			Score:            h.HealthScore(),
+			ConsecutiveFails: h.ConsecutiveFails,
+			TotalFails:       h.TotalFails,
+			TotalSuccess:     h.TotalSuccess,
+			LastError:        h.LastError,
+			LastCheckedAt:    h.LastCheckedAt,
+			NextEligibleAt:   h.NextEligibleAt,
+			EMALatencyMs:     h.EMALatencyMs,
+		})
+	}
+	return snapshots
 }
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUrlHealthScore(t *testing.T) {
+	h := &UrlHealth{}
+	if score := h.HealthScore(); score != 100 {
+		t.Fatalf("从未记录过的URL应为满分, got %v", score)
+	}
+
+	h = &UrlHealth{TotalSuccess: 10}
+	if score := h.HealthScore(); score != 100 {
+		t.Fatalf("全部成功且无延迟记录应为满分, got %v", score)
+	}
+
+	h = &UrlHealth{TotalSuccess: 5, TotalFails: 5, ConsecutiveFails: 3}
+	if score := h.HealthScore(); score <= 0 || score >= 100 {
+		t.Fatalf("部分失败应得到介于0到100之间的分数, got %v", score)
+	}
+
+	h = &UrlHealth{TotalFails: 10, ConsecutiveFails: 10}
+	if score := h.HealthScore(); score != 0 {
+		t.Fatalf("从未成功过且连续失败应接近0分, got %v", score)
+	}
+}
+
+func TestGetFailedUrlsRespectsWindow(t *testing.T) {
+	s := NewSubsState()
+	now := time.Now()
+
+	// url1: 连续失败达标，但最近一次成功发生在窗口内 —— 不应被判定为需要移除
+	s.Health["url1"] = &UrlHealth{ConsecutiveFails: 5, LastSuccessAt: now.Add(-time.Hour)}
+	// url2: 连续失败达标，最近一次成功发生在窗口之外 —— 应被判定为需要移除
+	s.Health["url2"] = &UrlHealth{ConsecutiveFails: 5, LastSuccessAt: now.Add(-8 * 24 * time.Hour)}
+	// url3: 从未成功过 —— 应被判定为需要移除
+	s.Health["url3"] = &UrlHealth{ConsecutiveFails: 5}
+	// url4: 连续失败次数未达标 —— 不应被移除
+	s.Health["url4"] = &UrlHealth{ConsecutiveFails: 1}
+
+	got := s.GetFailedUrls(5, 7*24*time.Hour)
+	want := map[string]bool{"url2": true, "url3": true}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want urls in %v", got, want)
+	}
+	for _, url := range got {
+		if !want[url] {
+			t.Fatalf("unexpected url in result: %s", url)
+		}
+	}
+}
+
+func TestGetFailedUrlsDefaultWindow(t *testing.T) {
+	s := NewSubsState()
+	now := time.Now()
+
+	// window<=0时应回退到默认窗口(7天)，而不是把lifetime TotalSuccess当成永久豁免
+	s.Health["old-success"] = &UrlHealth{ConsecutiveFails: 5, LastSuccessAt: now.Add(-30 * 24 * time.Hour)}
+	s.Health["recent-success"] = &UrlHealth{ConsecutiveFails: 5, LastSuccessAt: now.Add(-time.Minute)}
+
+	got := s.GetFailedUrls(5, 0)
+	if len(got) != 1 || got[0] != "old-success" {
+		t.Fatalf("got %v, want only [old-success]", got)
+	}
+}
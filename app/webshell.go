@@ -0,0 +1,261 @@
+package app
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beck-8/subs-check/check"
+	"github.com/beck-8/subs-check/config"
+	"github.com/gorilla/websocket"
+)
+
+// webShellUpgrader 的 CheckOrigin 只接受显式配置在 webshell.allowed-origins 中的来源，
+// 未配置任何allowed-origins时回退为同源校验，杜绝任意网站发起跨域WebSocket连接
+var webShellUpgrader = websocket.Upgrader{
+	CheckOrigin: checkWebShellOrigin,
+}
+
+func checkWebShellOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // 非浏览器客户端（如CLI工具）通常不带Origin头
+	}
+
+	allowed := config.GlobalConfig.WebShell.AllowedOrigins
+	if len(allowed) > 0 {
+		for _, o := range allowed {
+			if o == origin {
+				return true
+			}
+		}
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// webShellDefaultIdleTimeout 会话无输入时的默认空闲超时
+const webShellDefaultIdleTimeout = 5 * time.Minute
+
+// webShellRequest 是客户端通过WebSocket发送的单条指令
+type webShellRequest struct {
+	Type string `json:"type"` // "exec" 或 "resize"
+	Cmd  string `json:"cmd,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+}
+
+// webShellResponse 是服务端推送给客户端的一条输出
+type webShellResponse struct {
+	Type   string `json:"type"` // "stdout"、"stderr"、"error"、"exit"
+	Data   string `json:"data,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// webShellConn 包装 *websocket.Conn，串行化写入 —— gorilla/websocket 不允许并发写同一个连接，
+// 而 stdout/stderr 转发分别运行在两个goroutine里，都需要写回这个连接
+type webShellConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *webShellConn) writeJSON(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+// webShellArgSpec 描述某条诊断命令允许的参数形态：限定数量范围内的纯位置参数，不接受任何flag，
+// 从根本上堵死 `-o`/`--upload-file`/`--noproxy` 之类能绕过代理沙箱或读写任意文件的选项
+var webShellArgSpec = map[string]struct {
+	minArgs, maxArgs int
+}{
+	"curl":          {1, 1}, // curl <url>
+	"dig":           {1, 1}, // dig <domain>
+	"traceroute":    {1, 1}, // traceroute <host>
+	"speedtest":     {0, 0},
+	"openai-check":  {0, 1},
+	"netflix-check": {0, 1},
+}
+
+// validateWebShellArgs 校验参数数量与形态：必须全部是不以 '-' 开头的位置参数
+func validateWebShellArgs(bin string, args []string) error {
+	spec, ok := webShellArgSpec[bin]
+	if !ok {
+		return fmt.Errorf("命令 %q 没有配置参数规则", bin)
+	}
+	if len(args) < spec.minArgs || len(args) > spec.maxArgs {
+		return fmt.Errorf("命令 %q 期望 %d-%d 个参数，实际收到 %d 个", bin, spec.minArgs, spec.maxArgs, len(args))
+	}
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			return fmt.Errorf("不允许使用选项参数 %q", a)
+		}
+	}
+
+	if bin == "curl" {
+		u, err := url.Parse(args[0])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			// 拒绝 file:// 等本地scheme —— 这类请求根本不会经过HTTP_PROXY，会绕开代理沙箱直接读本地文件
+			return fmt.Errorf("curl只允许http/https地址，收到 %q", args[0])
+		}
+	}
+	return nil
+}
+
+// handleWebShell 处理 /api/shell，提供针对指定代理节点的受限诊断终端：
+// 客户端先选择代理名，之后每条命令执行时 HTTP_PROXY/HTTPS_PROXY/ALL_PROXY 环境变量都指向该代理的
+// 本地桥接地址；代理只经这些环境变量注入，不读取它们的程序（如 dig、traceroute）的流量不经过该代理。
+// 可执行的二进制受 webshell 配置块中的allowlist约束，参数受 webShellArgSpec 约束
+func (app *App) handleWebShell(w http.ResponseWriter, r *http.Request) {
+	cfg := config.GlobalConfig.WebShell
+	if !cfg.Enabled {
+		http.Error(w, "webshell未启用", http.StatusForbidden)
+		return
+	}
+
+	if !authenticateWebShell(cfg, r) {
+		http.Error(w, "未授权", http.StatusUnauthorized)
+		return
+	}
+
+	proxyName := r.URL.Query().Get("proxy")
+	if proxyName == "" {
+		http.Error(w, "缺少proxy参数", http.StatusBadRequest)
+		return
+	}
+
+	upstreamAddr, closeBridge, err := check.StartLocalProxyBridge(proxyName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("为代理 %s 建立本地桥接失败: %v", proxyName, err), http.StatusBadRequest)
+		return
+	}
+	defer closeBridge()
+
+	rawConn, err := webShellUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Error("升级WebShell连接失败", "error", err)
+		return
+	}
+	conn := &webShellConn{conn: rawConn}
+	defer rawConn.Close()
+
+	idleTimeout := cfg.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = webShellDefaultIdleTimeout
+	}
+
+	for {
+		rawConn.SetReadDeadline(time.Now().Add(idleTimeout))
+
+		var req webShellRequest
+		if err := rawConn.ReadJSON(&req); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Debug("WebShell连接关闭", "error", err)
+			}
+			return
+		}
+
+		switch req.Type {
+		case "resize":
+			// 诊断命令均为一次性执行，无交互式pty，resize仅用于客户端渲染提示，服务端无需处理
+			continue
+		case "exec":
+			app.runWebShellCommand(conn, cfg, req.Cmd, upstreamAddr)
+		default:
+			conn.writeJSON(webShellResponse{Type: "error", Detail: "未知指令类型: " + req.Type})
+		}
+	}
+}
+
+// authenticateWebShell 校验会话令牌，未配置 AuthToken 时拒绝一切请求 ——
+// 诊断shell默认不应在没有凭据的情况下对外暴露
+func authenticateWebShell(cfg config.WebShellConfig, r *http.Request) bool {
+	if cfg.AuthToken == "" {
+		return false
+	}
+	token := r.Header.Get("Authorization")
+	token = strings.TrimPrefix(token, "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AuthToken)) == 1
+}
+
+// runWebShellCommand 校验命令是否在allowlist内、参数是否合法，随后以代理的本地桥接地址
+// 作为上游代理执行
+func (app *App) runWebShellCommand(conn *webShellConn, cfg config.WebShellConfig, cmd string, upstreamAddr string) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return
+	}
+	bin, args := fields[0], fields[1:]
+
+	allowed := false
+	for _, a := range cfg.AllowedCommands {
+		if a == bin {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		conn.writeJSON(webShellResponse{Type: "error", Detail: fmt.Sprintf("命令 %q 不在允许列表中", bin)})
+		return
+	}
+
+	if err := validateWebShellArgs(bin, args); err != nil {
+		conn.writeJSON(webShellResponse{Type: "error", Detail: err.Error()})
+		return
+	}
+
+	c := exec.Command(bin, args...)
+	c.Env = append(c.Env,
+		"HTTP_PROXY="+upstreamAddr,
+		"HTTPS_PROXY="+upstreamAddr,
+		"ALL_PROXY="+upstreamAddr,
+	)
+
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		conn.writeJSON(webShellResponse{Type: "error", Detail: err.Error()})
+		return
+	}
+	stderr, err := c.StderrPipe()
+	if err != nil {
+		conn.writeJSON(webShellResponse{Type: "error", Detail: err.Error()})
+		return
+	}
+
+	if err := c.Start(); err != nil {
+		conn.writeJSON(webShellResponse{Type: "error", Detail: fmt.Sprintf("启动命令失败: %v", err)})
+		return
+	}
+
+	var streamWg sync.WaitGroup
+	streamPipe := func(typ string, scanner *bufio.Scanner) {
+		defer streamWg.Done()
+		for scanner.Scan() {
+			conn.writeJSON(webShellResponse{Type: typ, Data: scanner.Text()})
+		}
+	}
+	streamWg.Add(2)
+	go streamPipe("stdout", bufio.NewScanner(stdout))
+	go streamPipe("stderr", bufio.NewScanner(stderr))
+	streamWg.Wait()
+
+	exitDetail := ""
+	if err := c.Wait(); err != nil {
+		exitDetail = err.Error()
+	}
+	conn.writeJSON(webShellResponse{Type: "exit", Detail: exitDetail})
+}
@@ -0,0 +1,49 @@
+// Package config 负责加载与持有 subs-check 的全局运行配置
+package config
+
+import "time"
+
+// GitSourceConfig 是某个 "git+" 订阅源的附加配置，按仓库地址匹配，
+// 用于提供 ParseGitSourceURL 无法从订阅地址本身得到的凭据、sparse checkout 与独立调度信息
+type GitSourceConfig struct {
+	RepoURL      string `yaml:"repo-url"`
+	Token        string `yaml:"token,omitempty"`
+	SSHKeyPath   string `yaml:"ssh-key,omitempty"`
+	Sparse       bool   `yaml:"sparse,omitempty"`
+	CronOverride string `yaml:"cron,omitempty"` // 该订阅源独立的cron表达式，只在全局检测轮次中作为同步门槛，不会单独触发同步
+}
+
+// RegistryConfig 控制是否将本实例注册到 Consul/etcd，以及是否从中动态发现订阅列表
+type RegistryConfig struct {
+	Enabled     bool   `yaml:"enabled,omitempty"`
+	Backend     string `yaml:"backend,omitempty"` // "consul" 或 "etcd"
+	Address     string `yaml:"address,omitempty"`
+	Token       string `yaml:"token,omitempty"`
+	ServiceName string `yaml:"service-name,omitempty"`
+	ServiceTag  string `yaml:"service-tag,omitempty"`
+	HealthPath  string `yaml:"health-path,omitempty"`
+	KVPrefix    string `yaml:"kv-prefix,omitempty"`
+}
+
+// Config 是 subs-check 的全局运行配置
+type Config struct {
+	Proxy                   string            `yaml:"proxy,omitempty"`
+	CheckInterval           int               `yaml:"check-interval"`
+	CronExpression          string            `yaml:"cron-expression,omitempty"`
+	PrintProgress           bool              `yaml:"print-progress,omitempty"`
+	KeepSuccessProxies      bool              `yaml:"keep-success-proxies,omitempty"`
+	ListenPort              string            `yaml:"listen-port,omitempty"`
+	SubStorePort            string            `yaml:"sub-store-port,omitempty"`
+	SubUrls                 []string          `yaml:"sub-urls,omitempty"`
+	SubUrlsFailRemove       int               `yaml:"sub-urls-fail-remove,omitempty"`
+	SubUrlsFailRemoveWindow time.Duration     `yaml:"sub-urls-fail-remove-window,omitempty"` // 距最近一次抓取成功多久后才允许自动移除，<=0时默认7天
+	GitSources              []GitSourceConfig `yaml:"git-sources,omitempty"`
+	Registry                RegistryConfig    `yaml:"registry,omitempty"`
+	WebShell                WebShellConfig    `yaml:"webshell,omitempty"`
+}
+
+// GlobalConfig 是进程内唯一的配置实例，由 App.loadConfig 解析配置文件后填充
+var GlobalConfig Config
+
+// GlobalProxies 保存本轮及历史保留下来的成功代理节点（KeepSuccessProxies开启时）
+var GlobalProxies []any
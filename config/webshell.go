@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// WebShellConfig 控制 /api/shell 诊断终端的开关与安全边界
+type WebShellConfig struct {
+	Enabled         bool          `yaml:"enabled,omitempty"`
+	AllowedCommands []string      `yaml:"allowed-commands,omitempty"`
+	AllowedOrigins  []string      `yaml:"allowed-origins,omitempty"` // 允许发起WebSocket连接的Origin，留空则回退为同源校验
+	AuthToken       string        `yaml:"auth-token,omitempty"`      // 会话令牌，留空则拒绝所有连接
+	IdleTimeout     time.Duration `yaml:"idle-timeout,omitempty"`
+}
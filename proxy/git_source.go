@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/robfig/cron/v3"
+)
+
+// GitSourceAuth 描述拉取私有仓库所需的凭据
+type GitSourceAuth struct {
+	Token      string `yaml:"token,omitempty" json:"token,omitempty"`
+	SSHKeyPath string `yaml:"ssh-key,omitempty" json:"ssh-key,omitempty"`
+}
+
+// GitSource 表示一个 Git 仓库订阅源
+type GitSource struct {
+	Alias        string        // 用于缓存目录命名
+	RepoURL      string        // 仓库地址
+	Ref          string        // 分支/标签，默认为仓库默认分支
+	Paths        []string      // 仓库内的节点文件路径，支持多个
+	Sparse       bool          // 是否启用 sparse checkout，仅签出 Paths 所在目录
+	Auth         GitSourceAuth // 拉取凭据
+	CronOverride string        // 该订阅源独立的 cron 表达式，只在全局检测轮次中作为同步门槛，不会单独触发同步
+}
+
+// ParseGitSourceURL 解析 "git+" 前缀的订阅地址，返回仓库地址、ref 与文件路径
+// 形如: git+https://host/user/repo.git#branch:path/to/nodes.yaml
+//
+// repoURL 与 "#ref:path" 后缀之间以最后一个 '#' 切分、ref 与 path 之间以后缀里最后一个
+// ':' 切分，而不是用懒惰正则从前往后匹配 —— 否则像
+// git+https://git.internal:3000/user/repo.git#main:nodes.yaml 这种仓库地址自带端口号的
+// 场景，端口号里的 ':' 会被提前截断到 url 里去。
+func ParseGitSourceURL(raw string) (repoURL, ref, path string, ok bool) {
+	if !strings.HasPrefix(raw, "git+") {
+		return "", "", "", false
+	}
+	rest := strings.TrimPrefix(raw, "git+")
+	if rest == "" {
+		return "", "", "", false
+	}
+
+	hashIdx := strings.LastIndex(rest, "#")
+	if hashIdx == -1 {
+		return rest, "", "", true
+	}
+
+	repoURL = rest[:hashIdx]
+	suffix := rest[hashIdx+1:]
+	if repoURL == "" {
+		return "", "", "", false
+	}
+
+	if colonIdx := strings.LastIndex(suffix, ":"); colonIdx != -1 {
+		ref = suffix[:colonIdx]
+		path = suffix[colonIdx+1:]
+	} else {
+		ref = suffix
+	}
+
+	return repoURL, ref, path, true
+}
+
+// SafeAlias 将仓库地址转换为可用作目录名的别名
+func SafeAlias(repoURL string) string {
+	replacer := strings.NewReplacer("://", "_", "/", "_", ":", "_", "@", "_", ".", "_")
+	return replacer.Replace(repoURL)
+}
+
+// cacheDir 返回该仓库订阅源在本地的缓存目录: configDir/repo/<alias>/
+func (s *GitSource) cacheDir(configDir string) string {
+	return filepath.Join(configDir, "repo", s.Alias)
+}
+
+// authMethod 根据配置构造 go-git 认证方式，未配置凭据时返回 nil（匿名拉取）
+func (s *GitSource) authMethod() (transport.AuthMethod, error) {
+	switch {
+	case s.Auth.Token != "":
+		return &http.BasicAuth{
+			Username: "token", // 多数平台(GitHub/GitLab/Gitee)接受任意非空用户名+token作为密码
+			Password: s.Auth.Token,
+		}, nil
+	case s.Auth.SSHKeyPath != "":
+		auth, err := gitssh.NewPublicKeysFromFile("git", s.Auth.SSHKeyPath, "")
+		if err != nil {
+			return nil, fmt.Errorf("加载SSH密钥失败: %w", err)
+		}
+		return auth, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Sync 克隆或快进拉取仓库到本地缓存目录，返回节点文件在本地的绝对路径列表
+func (s *GitSource) Sync(configDir string) ([]string, error) {
+	dir := s.cacheDir(configDir)
+	auth, err := s.authMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		slog.Info("Git订阅源本地缓存不存在，执行克隆", "alias", s.Alias, "repo", s.RepoURL)
+		cloneOpts := &git.CloneOptions{
+			URL:          s.RepoURL,
+			Auth:         auth,
+			Depth:        1, // 浅克隆，只保留最新一次提交
+			SingleBranch: true,
+			Tags:         git.NoTags,
+		}
+		if s.Ref != "" {
+			cloneOpts.ReferenceName = plumbing.NewBranchReferenceName(s.Ref)
+		}
+
+		repo, err = git.PlainClone(dir, false, cloneOpts)
+		if err != nil {
+			return nil, fmt.Errorf("克隆Git订阅源失败: %w", err)
+		}
+
+		if s.Sparse {
+			if err := s.applySparseCheckout(repo); err != nil {
+				slog.Warn("设置sparse checkout失败，将使用完整工作区", "alias", s.Alias, "error", err)
+			}
+		}
+	} else {
+		wt, err := repo.Worktree()
+		if err != nil {
+			return nil, fmt.Errorf("获取Git工作区失败: %w", err)
+		}
+
+		pullOpts := &git.PullOptions{
+			RemoteName: "origin",
+			Auth:       auth,
+			Depth:      1,
+		}
+		if s.Ref != "" {
+			pullOpts.ReferenceName = plumbing.NewBranchReferenceName(s.Ref)
+		}
+
+		if err := wt.Pull(pullOpts); err != nil && err != git.NoErrAlreadyUpToDate {
+			return nil, fmt.Errorf("拉取Git订阅源失败: %w", err)
+		}
+	}
+
+	var files []string
+	for _, p := range s.Paths {
+		abs := filepath.Join(dir, p)
+		if _, err := os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("Git订阅源中未找到文件 %s: %w", p, err)
+		}
+		files = append(files, abs)
+	}
+
+	return files, nil
+}
+
+// applySparseCheckout 仅签出 Paths 所在的目录，减少工作区体积
+func (s *GitSource) applySparseCheckout(repo *git.Repository) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	dirs := make(map[string]struct{})
+	for _, p := range s.Paths {
+		dirs[filepath.Dir(p)] = struct{}{}
+	}
+	patterns := make([]string, 0, len(dirs))
+	for d := range dirs {
+		patterns = append(patterns, d+"/**")
+	}
+
+	return wt.Checkout(&git.CheckoutOptions{
+		SparseCheckoutDirectories: patterns,
+	})
+}
+
+// ShouldSync 判断该订阅源本轮是否到达该执行同步的时间点。未配置CronOverride时，调度完全
+// 交给调用方的全局间隔/cron控制，这里始终允许；配置了CronOverride时，按其自身的cron表达式
+// 计算下一个调度点，只有自lastSync起已经过了该调度点才允许本轮同步。该判断只在调用方的
+// 全局检测轮次中进行，不会单独触发同步，因此同步频率不会高于全局检测频率
+func (s *GitSource) ShouldSync(lastSync time.Time) bool {
+	if s.CronOverride == "" {
+		return true
+	}
+	schedule, err := cron.ParseStandard(s.CronOverride)
+	if err != nil {
+		slog.Warn("Git订阅源cron表达式解析失败，忽略独立调度，按全局间隔同步", "alias", s.Alias, "cron", s.CronOverride, "error", err)
+		return true
+	}
+	if lastSync.IsZero() {
+		return true
+	}
+	return !schedule.Next(lastSync).After(time.Now())
+}
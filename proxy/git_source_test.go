@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseGitSourceURL(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		repoURL string
+		ref     string
+		path    string
+		ok      bool
+	}{
+		{
+			name:    "无ref无path",
+			raw:     "git+https://github.com/user/repo.git",
+			repoURL: "https://github.com/user/repo.git",
+			ok:      true,
+		},
+		{
+			name:    "带ref与path",
+			raw:     "git+https://github.com/user/repo.git#main:nodes.yaml",
+			repoURL: "https://github.com/user/repo.git",
+			ref:     "main",
+			path:    "nodes.yaml",
+			ok:      true,
+		},
+		{
+			name:    "仓库地址自带端口号",
+			raw:     "git+https://git.internal:3000/user/repo.git#main:nodes.yaml",
+			repoURL: "https://git.internal:3000/user/repo.git",
+			ref:     "main",
+			path:    "nodes.yaml",
+			ok:      true,
+		},
+		{
+			name:    "只有ref没有path",
+			raw:     "git+https://github.com/user/repo.git#main",
+			repoURL: "https://github.com/user/repo.git",
+			ref:     "main",
+			ok:      true,
+		},
+		{
+			name: "非git+前缀",
+			raw:  "https://example.com/sub.yaml",
+			ok:   false,
+		},
+		{
+			name: "git+后为空",
+			raw:  "git+",
+			ok:   false,
+		},
+		{
+			name: "#之前仓库地址为空",
+			raw:  "git+#main:nodes.yaml",
+			ok:   false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repoURL, ref, path, ok := ParseGitSourceURL(c.raw)
+			if ok != c.ok {
+				t.Fatalf("ok = %v, want %v", ok, c.ok)
+			}
+			if !c.ok {
+				return
+			}
+			if repoURL != c.repoURL || ref != c.ref || path != c.path {
+				t.Fatalf("got (%q, %q, %q), want (%q, %q, %q)", repoURL, ref, path, c.repoURL, c.ref, c.path)
+			}
+		})
+	}
+}
+
+func TestGitSourceShouldSync(t *testing.T) {
+	s := &GitSource{Alias: "test", CronOverride: ""}
+	if !s.ShouldSync(time.Now()) {
+		t.Fatal("未配置CronOverride时应始终允许同步")
+	}
+
+	s = &GitSource{Alias: "test", CronOverride: "not a cron expression"}
+	if !s.ShouldSync(time.Now()) {
+		t.Fatal("cron表达式解析失败时应回退为允许同步")
+	}
+
+	s = &GitSource{Alias: "test", CronOverride: "0 0 * * *"} // 每天0点
+	if !s.ShouldSync(time.Time{}) {
+		t.Fatal("从未同步过时应允许同步")
+	}
+	if s.ShouldSync(time.Now()) {
+		t.Fatal("刚同步完、尚未到下一个调度点时不应允许同步")
+	}
+	if !s.ShouldSync(time.Now().Add(-25 * time.Hour)) {
+		t.Fatal("上次同步已超过一个调度周期时应允许同步")
+	}
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpProbeTimeout 是探测单个订阅URL可达性时的超时时间，独立于实际抓取订阅内容的超时设置，
+// 仅用于产出成功/失败信号驱动健康评分与退避
+const httpProbeTimeout = 15 * time.Second
+
+// ProbeSubUrl 对一个普通 http(s) 订阅地址发起一次GET请求，用于驱动订阅健康状态的成功/失败计数。
+// 请求体会被读取并丢弃，只关心是否拿到了2xx响应；调用方应将结果经 RecordFetch 计入本轮统计
+func ProbeSubUrl(rawURL string) (success bool, errMsg string, latency time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), httpProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err.Error(), time.Since(start)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err.Error(), time.Since(start)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	latency = time.Since(start)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Sprintf("HTTP状态码 %d", resp.StatusCode), latency
+	}
+	return true, "", latency
+}
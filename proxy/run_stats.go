@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// FetchStat 记录一次订阅URL抓取的结果，供订阅生命周期管理（健康评分、退避、自动移除）使用
+type FetchStat struct {
+	Url     string
+	Success bool
+	Err     string
+	Latency time.Duration
+}
+
+var (
+	runStatsMu sync.Mutex
+	runStats   []FetchStat
+
+	eligibilityMu      sync.RWMutex
+	eligibilityChecker func(url string) bool
+)
+
+// SetEligibilityChecker 注入订阅URL是否已度过退避期、允许被抓取的判断函数，
+// 通常由 app.SubsState.ShouldFetch 提供，避免 proxy 包反向依赖 app 包
+func SetEligibilityChecker(fn func(url string) bool) {
+	eligibilityMu.Lock()
+	defer eligibilityMu.Unlock()
+	eligibilityChecker = fn
+}
+
+// IsUrlEligible 判断该订阅URL当前是否允许被抓取；未注入判断函数时默认允许，
+// 以保证在未开启健康状态管理的场景下不影响既有抓取行为
+func IsUrlEligible(url string) bool {
+	eligibilityMu.RLock()
+	fn := eligibilityChecker
+	eligibilityMu.RUnlock()
+	if fn == nil {
+		return true
+	}
+	return fn(url)
+}
+
+// FilterEligibleUrls 过滤掉仍处于退避期内的订阅URL，调用方应在抓取前使用该结果，
+// 实现"退避期内跳过抓取"的语义
+func FilterEligibleUrls(urls []string) []string {
+	eligible := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if IsUrlEligible(url) {
+			eligible = append(eligible, url)
+		}
+	}
+	return eligible
+}
+
+// RecordFetch 记录一次订阅URL抓取的成功/失败、错误信息与耗时，
+// 累计结果由 GetAndResetRunStats 取出后交给订阅生命周期管理处理
+func RecordFetch(url string, success bool, err error, latency time.Duration) {
+	stat := FetchStat{Url: url, Success: success, Latency: latency}
+	if err != nil {
+		stat.Err = err.Error()
+	}
+
+	runStatsMu.Lock()
+	runStats = append(runStats, stat)
+	runStatsMu.Unlock()
+}
+
+// GetAndResetRunStats 返回并清空本轮累计的订阅抓取统计
+func GetAndResetRunStats() []FetchStat {
+	runStatsMu.Lock()
+	defer runStatsMu.Unlock()
+
+	stats := runStats
+	runStats = nil
+	return stats
+}